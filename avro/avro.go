@@ -0,0 +1,290 @@
+// Package avro derives Avro record schemas from a schema.Table, registers
+// them against a Confluent-compatible Schema Registry, and wraps encoded
+// rows in Confluent's wire format for publishing to Kafka.
+package avro
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/uttom41/go_server_client/schema"
+)
+
+// confluentMagicByte is the first byte of every message encoded in
+// Confluent's wire format.
+const confluentMagicByte = 0x00
+
+// avroFieldSchema is one field of a derived Avro record schema.
+type avroFieldSchema struct {
+	Name string      `json:"name"`
+	Type interface{} `json:"type"`
+}
+
+// avroRecordSchema is the JSON shape of an Avro record schema, enough of it
+// to describe the tables GetSchema produces.
+type avroRecordSchema struct {
+	Type   string            `json:"type"`
+	Name   string            `json:"name"`
+	Fields []avroFieldSchema `json:"fields"`
+}
+
+// DeriveSchema builds an Avro record schema (as a JSON string) from the
+// columns of table. MySQL types are mapped to a conservative set of Avro
+// primitives; nullable columns become a ["null", T] union so a missing
+// value round-trips as null rather than failing to encode.
+func DeriveSchema(table schema.Table) (string, error) {
+	record := avroRecordSchema{
+		Type: "record",
+		Name: avroName(table.Name),
+	}
+
+	for _, col := range table.Columns {
+		avroType := mysqlTypeToAvro(col.DataType)
+		var fieldType interface{} = avroType
+		if col.IsNullable {
+			fieldType = []interface{}{"null", avroType}
+		}
+		record.Fields = append(record.Fields, avroFieldSchema{Name: col.Name, Type: fieldType})
+	}
+
+	out, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("marshaling avro schema for %s: %w", table.Name, err)
+	}
+	return string(out), nil
+}
+
+// avroName strips the backtick-quoting GetSchema adds around table names;
+// Avro record names must be plain identifiers.
+func avroName(tableName string) string {
+	return trimBackticks(tableName)
+}
+
+func trimBackticks(s string) string {
+	if len(s) >= 2 && s[0] == '`' && s[len(s)-1] == '`' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// mysqlTypeToAvro maps a DESCRIBE column type to an Avro primitive. Unknown
+// types fall back to "string", which is always a safe (if lossy)
+// representation.
+func mysqlTypeToAvro(mysqlType string) string {
+	switch {
+	case containsAny(mysqlType, "int", "bigint", "smallint", "tinyint", "mediumint"):
+		return "long"
+	case containsAny(mysqlType, "float"):
+		return "float"
+	case containsAny(mysqlType, "double", "decimal"):
+		return "double"
+	case containsAny(mysqlType, "bool"):
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if len(s) >= len(sub) {
+			for i := 0; i+len(sub) <= len(s); i++ {
+				if s[i:i+len(sub)] == sub {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// RegistryConfig configures access to a Confluent-compatible Schema
+// Registry.
+type RegistryConfig struct {
+	URL      string
+	Username string
+	Password string
+	// IDCacheTTL bounds how long a (subject, fingerprint) -> schema ID
+	// mapping is trusted before Register hits the registry again.
+	IDCacheTTL time.Duration
+}
+
+type cacheEntry struct {
+	schemaID  int
+	expiresAt time.Time
+}
+
+// Registry registers Avro schemas with a Schema Registry and caches the
+// returned IDs locally so steady-state publishing never has to make a
+// network round trip per message.
+type Registry struct {
+	cfg    RegistryConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry // key: subject + "#" + fingerprint
+}
+
+// NewRegistry returns a Registry ready to register schemas against cfg.URL.
+func NewRegistry(cfg RegistryConfig) *Registry {
+	return &Registry{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[string]cacheEntry),
+	}
+}
+
+// Register returns the schema ID for schemaJSON under the registry subject
+// "<subject>-value", registering it with the Schema Registry if it isn't
+// already cached locally.
+func (r *Registry) Register(subject, schemaJSON string) (int, error) {
+	fingerprint := fingerprintSchema(schemaJSON)
+	key := subject + "#" + fingerprint
+
+	r.mu.Lock()
+	if entry, ok := r.cache[key]; ok && time.Now().Before(entry.expiresAt) {
+		r.mu.Unlock()
+		return entry.schemaID, nil
+	}
+	r.mu.Unlock()
+
+	id, err := r.registerRemote(subject, schemaJSON)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	r.cache[key] = cacheEntry{schemaID: id, expiresAt: time.Now().Add(r.cfg.IDCacheTTL)}
+	r.mu.Unlock()
+
+	return id, nil
+}
+
+func (r *Registry) registerRemote(subject, schemaJSON string) (int, error) {
+	body, err := json.Marshal(map[string]string{"schema": schemaJSON})
+	if err != nil {
+		return 0, err
+	}
+
+	url := fmt.Sprintf("%s/subjects/%s-value/versions", r.cfg.URL, subject)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if r.cfg.Username != "" {
+		req.Header.Set("Authorization", "Basic "+basicAuth(r.cfg.Username, r.cfg.Password))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("registering schema for subject %s: %w", subject, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("schema registry returned %s for subject %s", resp.Status, subject)
+	}
+
+	var out struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decoding schema registry response: %w", err)
+	}
+	return out.ID, nil
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+func fingerprintSchema(schemaJSON string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(schemaJSON))
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// Encoder turns rows into Confluent-wire-format Kafka message values for a
+// single Avro schema.
+type Encoder struct {
+	schemaID int
+	codec    *goavro.Codec
+	// unionFields maps the name of every nullable (["null", T]) field to
+	// its non-null branch type, so Encode can wrap values the way goavro
+	// requires for a union.
+	unionFields map[string]string
+}
+
+// NewEncoder compiles schemaJSON and pairs it with the schema ID the
+// registry returned for it.
+func NewEncoder(schemaID int, schemaJSON string) (*Encoder, error) {
+	codec, err := goavro.NewCodec(schemaJSON)
+	if err != nil {
+		return nil, fmt.Errorf("compiling avro codec: %w", err)
+	}
+
+	var record avroRecordSchema
+	if err := json.Unmarshal([]byte(schemaJSON), &record); err != nil {
+		return nil, fmt.Errorf("parsing avro schema: %w", err)
+	}
+
+	unionFields := make(map[string]string)
+	for _, field := range record.Fields {
+		branches, ok := field.Type.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, branch := range branches {
+			if name, ok := branch.(string); ok && name != "null" {
+				unionFields[field.Name] = name
+			}
+		}
+	}
+
+	return &Encoder{schemaID: schemaID, codec: codec, unionFields: unionFields}, nil
+}
+
+// Encode serializes row as Avro binary and prepends the Confluent wire
+// format header: magic byte 0x00, then the 4-byte big-endian schema ID.
+func (e *Encoder) Encode(row map[string]interface{}) ([]byte, error) {
+	native := row
+	if len(e.unionFields) > 0 {
+		// goavro requires a non-nil union value to be wrapped as
+		// map[string]interface{}{branchType: value}; a nil value is
+		// passed through as-is to select the "null" branch. DeriveSchema
+		// makes every nullable column such a union, so avoid mutating
+		// the caller's row by copying before wrapping.
+		native = make(map[string]interface{}, len(row))
+		for k, v := range row {
+			native[k] = v
+		}
+		for field, branchType := range e.unionFields {
+			if v, ok := native[field]; ok && v != nil {
+				native[field] = map[string]interface{}{branchType: v}
+			}
+		}
+	}
+
+	avroBinary, err := e.codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, fmt.Errorf("encoding row as avro: %w", err)
+	}
+
+	out := make([]byte, 0, 5+len(avroBinary))
+	out = append(out, confluentMagicByte)
+	idBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(idBytes, uint32(e.schemaID))
+	out = append(out, idBytes...)
+	out = append(out, avroBinary...)
+	return out, nil
+}
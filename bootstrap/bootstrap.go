@@ -0,0 +1,248 @@
+// Package bootstrap gives a brand-new consumer a consistent initial copy
+// of each tracked table before CDC streaming begins, as JSON Lines. It is
+// resumable: progress is persisted into tracking_table so a crash mid-dump
+// continues instead of restarting from scratch.
+package bootstrap
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/uttom41/go_server_client/position"
+)
+
+// defaultBatchSize is how many rows Bootstrap reads from a table per
+// round-trip.
+const defaultBatchSize = 1000
+
+// BootstrapSink receives the rows (and final position) of a table
+// snapshot.
+type BootstrapSink interface {
+	// WriteRow is called once per row, serialized as it will appear on the
+	// wire (one line of JSON).
+	WriteRow(table string, rowJSON []byte) error
+	// Done is called once per table, after its last row, with the binlog
+	// position captured at snapshot start so CDC knows where to resume
+	// from.
+	Done(table string, pos position.Binlog) error
+}
+
+// progressStore persists (table, last_pk) so a crashed dump resumes
+// instead of restarting.
+type progressStore struct {
+	db *gorm.DB
+}
+
+// load returns the last PK dumped for table and the binlog position
+// captured when its snapshot started, if either has been persisted yet.
+func (p progressStore) load(table string) (int64, position.Binlog, error) {
+	var lastPK sql.NullInt64
+	var pos position.Binlog
+	var serverUUID, binlogFile, gtidSet sql.NullString
+	var binlogPos sql.NullInt64
+	row := p.db.Raw("SELECT last_sent_id, server_uuid, binlog_file, binlog_pos, gtid_set FROM tracking_table WHERE table_name = ?", table).Row()
+	if err := row.Scan(&lastPK, &serverUUID, &binlogFile, &binlogPos, &gtidSet); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, pos, nil
+		}
+		return 0, pos, err
+	}
+	pos.ServerUUID = serverUUID.String
+	pos.File = binlogFile.String
+	pos.Pos = uint32(binlogPos.Int64)
+	pos.GTIDSet = gtidSet.String
+	return lastPK.Int64, pos, nil
+}
+
+func (p progressStore) save(table string, lastPK int64) error {
+	result := p.db.Exec(`
+        INSERT INTO tracking_table (table_name, last_sent_id)
+        VALUES (?, ?)
+        ON DUPLICATE KEY UPDATE last_sent_id = ?
+    `, table, lastPK, lastPK)
+	return result.Error
+}
+
+// savePosition persists the binlog position a table's snapshot started
+// from. It is written once per table, the first time bootstrapTable runs
+// for it, so a crash-resume reuses the same position instead of capturing
+// a new one that rows already dumped under the original snapshot would
+// never replay against.
+func (p progressStore) savePosition(table string, pos position.Binlog) error {
+	result := p.db.Exec(`
+        INSERT INTO tracking_table (table_name, server_uuid, binlog_file, binlog_pos, gtid_set)
+        VALUES (?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE server_uuid = ?, binlog_file = ?, binlog_pos = ?, gtid_set = ?
+    `, table, pos.ServerUUID, pos.File, pos.Pos, pos.GTIDSet,
+		pos.ServerUUID, pos.File, pos.Pos, pos.GTIDSet)
+	return result.Error
+}
+
+// Bootstrap snapshots each of tables in turn and streams it to sink as
+// JSON Lines, resuming from whatever (table, last_pk) tracking_table
+// already has recorded.
+func Bootstrap(db *gorm.DB, tables []string, sink BootstrapSink) error {
+	progress := progressStore{db: db}
+
+	for _, table := range tables {
+		if err := bootstrapTable(db, progress, table, sink); err != nil {
+			return fmt.Errorf("bootstrapping %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func bootstrapTable(db *gorm.DB, progress progressStore, table string, sink BootstrapSink) error {
+	lastPK, pos, err := progress.load(table)
+	if err != nil {
+		return err
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.Rollback()
+
+	if err := tx.Exec("START TRANSACTION WITH CONSISTENT SNAPSHOT").Error; err != nil {
+		return err
+	}
+
+	if pos.File == "" {
+		// First attempt at this table: capture the position its snapshot
+		// is consistent as of and persist it immediately, so a crash
+		// mid-dump resumes against this same position instead of one
+		// captured later, which would miss replaying rows that were
+		// already dumped but changed in between.
+		pos, err = capturePosition(tx)
+		if err != nil {
+			return err
+		}
+		if err := progress.savePosition(table, pos); err != nil {
+			return err
+		}
+	}
+
+	pkColumn, err := primaryKeyColumn(tx, table)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rows, maxPK, n, err := fetchBatch(tx, table, pkColumn, lastPK, defaultBatchSize)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		for _, row := range rows {
+			rowJSON, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if err := sink.WriteRow(table, rowJSON); err != nil {
+				return err
+			}
+		}
+
+		lastPK = maxPK
+		if err := progress.save(table, lastPK); err != nil {
+			return err
+		}
+
+		if n < defaultBatchSize {
+			break
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return sink.Done(table, pos)
+}
+
+// capturePosition records the binlog position as of snapshot start, so the
+// CDC subsystem knows exactly where to resume once bootstrap hands off.
+func capturePosition(tx *gorm.DB) (position.Binlog, error) {
+	var pos position.Binlog
+	var binlogDoDB, binlogIgnoreDB, executedGtidSet sql.NullString
+	row := tx.Raw("SHOW MASTER STATUS").Row()
+	if err := row.Scan(&pos.File, &pos.Pos, &binlogDoDB, &binlogIgnoreDB, &executedGtidSet); err != nil {
+		return pos, fmt.Errorf("reading SHOW MASTER STATUS: %w", err)
+	}
+	pos.GTIDSet = executedGtidSet.String
+	return pos, nil
+}
+
+// primaryKeyColumn returns the name of table's primary key column, so
+// fetchBatch can paginate on the column that actually identifies its rows
+// instead of assuming every table has an int64 "id" column. fetchBatch's
+// cursor is an int64, so a non-integer PK (UUID, varchar, ...) is rejected
+// here rather than silently looping forever re-dumping the same rows.
+func primaryKeyColumn(tx *gorm.DB, table string) (string, error) {
+	rows, err := tx.Raw(fmt.Sprintf("DESCRIBE %s", table)).Rows()
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field, colType, null, key string
+		var defaultValue sql.NullString
+		var extra string
+		if err := rows.Scan(&field, &colType, &null, &key, &defaultValue, &extra); err != nil {
+			return "", err
+		}
+		if key != "PRI" {
+			continue
+		}
+		if !strings.Contains(colType, "int") {
+			return "", fmt.Errorf("table %s has a non-integer primary key %q (%s), which bootstrap's cursor-based pagination does not support", table, field, colType)
+		}
+		return field, nil
+	}
+	return "", fmt.Errorf("table %s has no primary key", table)
+}
+
+// fetchBatch reads up to batchSize rows of table ordered by pkColumn,
+// starting after lastPK.
+func fetchBatch(tx *gorm.DB, table, pkColumn string, lastPK int64, batchSize int) ([]map[string]interface{}, int64, int, error) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s > ? ORDER BY %s ASC LIMIT ?", table, pkColumn, pkColumn)
+	rows, err := tx.Raw(query, lastPK, batchSize).Rows()
+	if err != nil {
+		return nil, lastPK, 0, err
+	}
+	defer rows.Close()
+
+	var data []map[string]interface{}
+	maxPK := lastPK
+	for rows.Next() {
+		columns, _ := rows.Columns()
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, lastPK, 0, err
+		}
+
+		entry := make(map[string]interface{})
+		for i, col := range columns {
+			entry[col] = *(values[i].(*interface{}))
+		}
+		data = append(data, entry)
+
+		if id, ok := entry[pkColumn].(int64); ok && id > maxPK {
+			maxPK = id
+		}
+	}
+
+	return data, maxPK, len(data), nil
+}
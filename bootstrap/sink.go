@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/uttom41/go_server_client/position"
+)
+
+// snapshotDoneMarker is published to a KafkaBootstrapSink's topic after a
+// table's last row, carrying the binlog position CDC should resume from.
+type snapshotDoneMarker struct {
+	SnapshotDone bool            `json:"snapshot_done"`
+	Position     position.Binlog `json:"position"`
+}
+
+// KafkaBootstrapSink publishes one message per row to
+// "<topic>.snapshot", followed by a snapshot_done marker carrying the
+// captured binlog position.
+type KafkaBootstrapSink struct {
+	Writer *kafka.Writer
+}
+
+func (k KafkaBootstrapSink) WriteRow(table string, rowJSON []byte) error {
+	return k.Writer.WriteMessages(context.Background(), kafka.Message{
+		Topic: table + ".snapshot",
+		Value: rowJSON,
+	})
+}
+
+func (k KafkaBootstrapSink) Done(table string, pos position.Binlog) error {
+	marker, err := json.Marshal(snapshotDoneMarker{SnapshotDone: true, Position: pos})
+	if err != nil {
+		return err
+	}
+	return k.Writer.WriteMessages(context.Background(), kafka.Message{
+		Topic: table + ".snapshot",
+		Value: marker,
+	})
+}
+
+// FileBootstrapSink writes "<table>.jsonl" files under Dir for offline
+// reload, one JSON object per line.
+type FileBootstrapSink struct {
+	Dir   string
+	files map[string]*os.File
+}
+
+// NewFileBootstrapSink prepares a sink that writes under dir, creating it
+// if necessary.
+func NewFileBootstrapSink(dir string) (*FileBootstrapSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating bootstrap dir %s: %w", dir, err)
+	}
+	return &FileBootstrapSink{Dir: dir, files: make(map[string]*os.File)}, nil
+}
+
+func (f *FileBootstrapSink) file(table string) (*os.File, error) {
+	if fh, ok := f.files[table]; ok {
+		return fh, nil
+	}
+	fh, err := os.OpenFile(filepath.Join(f.Dir, table+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f.files[table] = fh
+	return fh, nil
+}
+
+func (f *FileBootstrapSink) WriteRow(table string, rowJSON []byte) error {
+	fh, err := f.file(table)
+	if err != nil {
+		return err
+	}
+	_, err = fh.Write(append(rowJSON, '\n'))
+	return err
+}
+
+func (f *FileBootstrapSink) Done(table string, pos position.Binlog) error {
+	fh, ok := f.files[table]
+	if !ok {
+		return nil
+	}
+	delete(f.files, table)
+	return fh.Close()
+}
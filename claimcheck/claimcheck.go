@@ -0,0 +1,111 @@
+// Package claimcheck offloads oversized Kafka payloads to an object store
+// and publishes a small envelope pointing at the upload instead, so
+// consumers never have to buffer and reassemble multi-part messages.
+package claimcheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Envelope is the Kafka message value published in place of an oversized
+// payload.
+type Envelope struct {
+	ClaimCheck bool   `json:"claim_check"`
+	URI        string `json:"uri"`
+	SHA256     string `json:"sha256"`
+	Size       int    `json:"size"`
+}
+
+// ExternalStorage is the minimal object-store surface claim-checking needs,
+// implemented by an S3/GCS/MinIO client.
+type ExternalStorage interface {
+	PutObject(ctx context.Context, key string, body []byte) error
+	GetObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// Config controls when payloads are claim-checked and where they land.
+type Config struct {
+	// Threshold is the serialized-payload size, in bytes, above which a
+	// payload is offloaded instead of published inline.
+	Threshold int
+	Bucket    string
+	// KeyPrefix is prepended to every generated object key.
+	KeyPrefix string
+}
+
+// ShouldClaimCheck reports whether a payload of this size should be
+// offloaded rather than published inline.
+func (c Config) ShouldClaimCheck(payloadSize int) bool {
+	return payloadSize > c.Threshold
+}
+
+// Publish uploads payload to storage under a generated key and returns the
+// envelope to publish to Kafka in its place.
+func Publish(ctx context.Context, storage ExternalStorage, cfg Config, payload []byte) (Envelope, error) {
+	sum := sha256.Sum256(payload)
+	key := objectKey(cfg.KeyPrefix)
+
+	if err := storage.PutObject(ctx, key, payload); err != nil {
+		return Envelope{}, fmt.Errorf("uploading claim-check payload: %w", err)
+	}
+
+	return Envelope{
+		ClaimCheck: true,
+		URI:        fmt.Sprintf("s3://%s/%s", cfg.Bucket, key),
+		SHA256:     hex.EncodeToString(sum[:]),
+		Size:       len(payload),
+	}, nil
+}
+
+// Fetch downloads the payload an envelope points at and verifies its
+// SHA-256 before handing it back, so a corrupted or truncated upload is
+// caught instead of silently decoded.
+func Fetch(ctx context.Context, storage ExternalStorage, env Envelope) ([]byte, error) {
+	key := objectKeyFromURI(env.URI)
+
+	payload, err := storage.GetObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("fetching claim-check payload %s: %w", env.URI, err)
+	}
+
+	sum := sha256.Sum256(payload)
+	if got := hex.EncodeToString(sum[:]); got != env.SHA256 {
+		return nil, fmt.Errorf("claim-check payload %s failed sha256 verification: got %s, want %s", env.URI, got, env.SHA256)
+	}
+
+	return payload, nil
+}
+
+// Decode reports whether value is a claim-check envelope and, if so,
+// decodes it.
+func Decode(value []byte) (Envelope, bool) {
+	var env Envelope
+	if err := json.Unmarshal(value, &env); err != nil {
+		return Envelope{}, false
+	}
+	return env, env.ClaimCheck
+}
+
+func objectKey(prefix string) string {
+	return fmt.Sprintf("%s%d-%016x", prefix, time.Now().UnixNano(), rand.Uint64())
+}
+
+func objectKeyFromURI(uri string) string {
+	idx := strings.Index(uri, "://")
+	if idx < 0 {
+		return uri
+	}
+	rest := uri[idx+3:]
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) != 2 {
+		return rest
+	}
+	return parts[1]
+}
@@ -0,0 +1,114 @@
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// retryableS3Codes are the S3 error codes CDC bursts routinely hit when
+// they throttle the bucket; everything else is treated as permanent.
+var retryableS3Codes = map[string]bool{
+	"RequestTimeout": true,
+	"SlowDown":       true,
+}
+
+// S3Retryer retries S3 calls that fail with a throttling or 5xx error,
+// backing off exponentially starting from MinDelay.
+type S3Retryer struct {
+	MaxRetries int
+	MinDelay   time.Duration
+}
+
+// NewS3Retryer returns the backlog's default policy: 10 retries, 10s
+// minimum delay, doubling each attempt.
+func NewS3Retryer() S3Retryer {
+	return S3Retryer{MaxRetries: 10, MinDelay: 10 * time.Second}
+}
+
+func (r S3Retryer) shouldRetry(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	if retryableS3Codes[aerr.Code()] {
+		return true
+	}
+	if reqErr, ok := aerr.(awserr.RequestFailure); ok && reqErr.StatusCode() >= 500 {
+		return true
+	}
+	return false
+}
+
+func (r S3Retryer) delay(attempt int) time.Duration {
+	return r.MinDelay * time.Duration(math.Pow(2, float64(attempt)))
+}
+
+// do runs op, retrying per the configured policy.
+func (r S3Retryer) do(op func() error) error {
+	var err error
+	for attempt := 0; attempt <= r.MaxRetries; attempt++ {
+		if err = op(); err == nil {
+			return nil
+		}
+		if !r.shouldRetry(err) || attempt == r.MaxRetries {
+			return err
+		}
+		time.Sleep(r.delay(attempt))
+	}
+	return err
+}
+
+// S3Storage implements ExternalStorage against a single S3 (or
+// S3-compatible, e.g. MinIO) bucket.
+type S3Storage struct {
+	client  *s3.S3
+	bucket  string
+	retryer S3Retryer
+}
+
+// NewS3Storage builds an S3Storage for bucket using the given session
+// (pointed at MinIO/GCS's S3-compatible endpoint via its Config as needed).
+func NewS3Storage(sess *session.Session, bucket string, retryer S3Retryer) *S3Storage {
+	return &S3Storage{client: s3.New(sess), bucket: bucket, retryer: retryer}
+}
+
+func (s *S3Storage) PutObject(ctx context.Context, key string, body []byte) error {
+	return s.retryer.do(func() error {
+		_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		})
+		return err
+	})
+}
+
+func (s *S3Storage) GetObject(ctx context.Context, key string) ([]byte, error) {
+	var body []byte
+	err := s.retryer.do(func() error {
+		out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+		defer out.Body.Close()
+
+		body, err = ioutil.ReadAll(out.Body)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting s3://%s/%s: %w", s.bucket, key, err)
+	}
+	return body, nil
+}
@@ -0,0 +1,373 @@
+// Package consumer reassembles the multi-part messages sendSchemaInChunks
+// produces on the producer side, and commits offsets only once a message
+// is fully reassembled and handled, so a crash mid-reassembly reprocesses
+// every part instead of silently dropping one.
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// DeadLetter receives schema IDs whose parts never completed within
+// Reassembler's PartTimeout.
+type DeadLetter interface {
+	Send(schemaID string, headers map[string]string, parts map[int][]byte) error
+}
+
+// CommitResult mirrors librdkafka's CommitMessage return shape, so
+// operators can trace which batch a reassembled message was finalized
+// from.
+type CommitResult struct {
+	Topic     string
+	Partition int
+	Offset    int64
+}
+
+// Handler processes one fully reassembled message. Returning a non-nil
+// error leaves the offset uncommitted, so every part is redelivered and
+// reprocessed after a restart.
+type Handler func(fullPayload []byte, headers map[string]string) error
+
+// offsetRef identifies one Kafka message a part was read from, so its
+// partition's commit watermark can be released once the part's message is
+// reassembled (or abandoned).
+type offsetRef struct {
+	topic     string
+	partition int
+	offset    int64
+}
+
+type partial struct {
+	headers    map[string]string
+	totalParts int
+	parts      map[int][]byte
+	offsets    []offsetRef
+	size       int
+	firstSeen  time.Time
+}
+
+func (p *partial) complete() bool {
+	return len(p.parts) == p.totalParts
+}
+
+func (p *partial) payload() []byte {
+	var out []byte
+	for i := 0; i < p.totalParts; i++ {
+		out = append(out, p.parts[i]...)
+	}
+	return out
+}
+
+// partitionProgress tracks, for one Kafka partition, which consumed
+// offsets still belong to an incomplete message. Parts of different
+// schema IDs interleave on a partition, so committing a just-finished
+// message's own last offset isn't safe - an earlier, still-incomplete
+// message's parts might sit at lower offsets and would never be
+// redelivered after a crash. The commit watermark never advances past the
+// lowest outstanding offset.
+type partitionProgress struct {
+	outstanding map[int64]struct{}
+	maxSeen     int64
+	committed   int64
+}
+
+func partitionKey(topic string, partition int) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
+
+// Reassembler buffers the parts of multi-part Kafka messages, keyed by
+// their schema_id header, until every part 0..total_parts-1 has arrived.
+type Reassembler struct {
+	Reader *kafka.Reader
+
+	// MaxBufferBytes bounds how much unreassembled data is held in memory
+	// across all in-flight schema IDs. When a new part would exceed it,
+	// the oldest buffered schema ID is evicted and a warning logged.
+	MaxBufferBytes int
+
+	// PartTimeout is how long a schema ID may sit incomplete before it is
+	// handed to DeadLetter instead of waited on further.
+	PartTimeout time.Duration
+
+	DeadLetter DeadLetter
+
+	mu            sync.Mutex
+	buffers       map[string]*partial
+	order         []string // oldest-first order of schema IDs, for LRU eviction
+	bufferedBytes int
+	progress      map[string]*partitionProgress // keyed by partitionKey
+}
+
+// Consume blocks, fetching messages from Reader and feeding their parts
+// into the reassembly buffer, invoking handler once per completed message.
+// It returns only when ctx is cancelled or FetchMessage returns an error.
+func (r *Reassembler) Consume(ctx context.Context, handler Handler) error {
+	r.mu.Lock()
+	if r.buffers == nil {
+		r.buffers = make(map[string]*partial)
+	}
+	if r.progress == nil {
+		r.progress = make(map[string]*partitionProgress)
+	}
+	r.mu.Unlock()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go r.sweepExpired(stop)
+
+	for {
+		msg, err := r.Reader.FetchMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("fetching message: %w", err)
+		}
+
+		result, err := r.ingest(msg, handler)
+		if err != nil {
+			log.Println("Error reassembling message:", err)
+			continue
+		}
+		if result != nil {
+			log.Printf("Committed reassembled message at %s[%d]@%d\n", result.Topic, result.Partition, result.Offset)
+		}
+	}
+}
+
+func (r *Reassembler) ingest(msg kafka.Message, handler Handler) (*CommitResult, error) {
+	headers := headerMap(msg.Headers)
+
+	schemaID, ok := headers["schema_id"]
+	if !ok {
+		return nil, fmt.Errorf("message at %s[%d]@%d has no schema_id header", msg.Topic, msg.Partition, msg.Offset)
+	}
+	partNum, err := strconv.Atoi(headers["part_number"])
+	if err != nil {
+		return nil, fmt.Errorf("message %s has invalid part_number: %w", schemaID, err)
+	}
+	totalParts, err := strconv.Atoi(headers["total_parts"])
+	if err != nil {
+		return nil, fmt.Errorf("message %s has invalid total_parts: %w", schemaID, err)
+	}
+
+	r.mu.Lock()
+	buf, ok := r.buffers[schemaID]
+	if !ok {
+		buf = &partial{headers: headers, totalParts: totalParts, parts: make(map[int][]byte), firstSeen: time.Now()}
+		r.buffers[schemaID] = buf
+		r.order = append(r.order, schemaID)
+	}
+	buf.parts[partNum] = msg.Value
+	buf.offsets = append(buf.offsets, offsetRef{topic: msg.Topic, partition: msg.Partition, offset: msg.Offset})
+	buf.size += len(msg.Value)
+	r.bufferedBytes += len(msg.Value)
+
+	prog := r.progressForLocked(msg.Topic, msg.Partition)
+	prog.outstanding[msg.Offset] = struct{}{}
+	if msg.Offset > prog.maxSeen {
+		prog.maxSeen = msg.Offset
+	}
+
+	r.evictIfOverCapacity(schemaID)
+	complete := buf.complete()
+	r.mu.Unlock()
+
+	if !complete {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	payload := buf.payload()
+	r.mu.Unlock()
+
+	if err := handler(payload, headers); err != nil {
+		return nil, fmt.Errorf("handling reassembled message %s: %w", schemaID, err)
+	}
+
+	r.mu.Lock()
+	offsets := r.removeLocked(schemaID)
+	toCommit := r.releaseOffsetsLocked(offsets)
+	r.mu.Unlock()
+
+	var result *CommitResult
+	for _, m := range toCommit {
+		if err := r.Reader.CommitMessages(context.Background(), m); err != nil {
+			return nil, fmt.Errorf("committing offset for %s: %w", schemaID, err)
+		}
+		result = &CommitResult{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset}
+	}
+
+	return result, nil
+}
+
+// progressForLocked returns (creating if necessary) the partitionProgress
+// for (topic, partition). Callers must hold r.mu.
+func (r *Reassembler) progressForLocked(topic string, partition int) *partitionProgress {
+	pk := partitionKey(topic, partition)
+	prog, ok := r.progress[pk]
+	if !ok {
+		prog = &partitionProgress{outstanding: make(map[int64]struct{}), committed: -1}
+		r.progress[pk] = prog
+	}
+	return prog
+}
+
+// releaseOffsetsLocked marks offsets as no longer outstanding and returns,
+// per partition that became safe to advance, the message to commit up to.
+// Callers must hold r.mu, and must call Reader.CommitMessages for the
+// result outside the lock.
+func (r *Reassembler) releaseOffsetsLocked(offsets []offsetRef) []kafka.Message {
+	var toCommit []kafka.Message
+	done := make(map[string]bool, len(offsets))
+	var released []offsetRef // one entry per partition touched
+	for _, o := range offsets {
+		pk := partitionKey(o.topic, o.partition)
+		prog, ok := r.progress[pk]
+		if !ok {
+			continue
+		}
+		delete(prog.outstanding, o.offset)
+		if !done[pk] {
+			done[pk] = true
+			released = append(released, o)
+		}
+	}
+
+	for _, o := range released {
+		prog := r.progress[partitionKey(o.topic, o.partition)]
+		target := prog.maxSeen
+		if lowest, any := minOutstanding(prog.outstanding); any {
+			target = lowest - 1
+		}
+		if target > prog.committed {
+			prog.committed = target
+			toCommit = append(toCommit, kafka.Message{Topic: o.topic, Partition: o.partition, Offset: target})
+		}
+	}
+	return toCommit
+}
+
+func minOutstanding(outstanding map[int64]struct{}) (int64, bool) {
+	var lowest int64
+	found := false
+	for offset := range outstanding {
+		if !found || offset < lowest {
+			lowest = offset
+			found = true
+		}
+	}
+	return lowest, found
+}
+
+// evictIfOverCapacity drops the oldest buffered schema ID(s) until
+// bufferedBytes is back within MaxBufferBytes. Callers must hold r.mu.
+func (r *Reassembler) evictIfOverCapacity(justAdded string) {
+	if r.MaxBufferBytes <= 0 {
+		return
+	}
+	for r.bufferedBytes > r.MaxBufferBytes && len(r.order) > 0 {
+		oldest := r.order[0]
+		if oldest == justAdded && len(r.order) == 1 {
+			break // nothing else to evict
+		}
+		log.Printf("Warning: reassembly buffer over %d bytes, evicting incomplete schema_id=%s\n", r.MaxBufferBytes, oldest)
+		offsets := r.removeLocked(oldest)
+		r.forgetOutstandingLocked(offsets)
+	}
+}
+
+// removeLocked drops schemaID's buffer and returns the offsets it held, so
+// the caller can release them from their partitions' outstanding sets.
+// Callers must hold r.mu.
+func (r *Reassembler) removeLocked(schemaID string) []offsetRef {
+	buf, ok := r.buffers[schemaID]
+	if !ok {
+		return nil
+	}
+	r.bufferedBytes -= buf.size
+	delete(r.buffers, schemaID)
+	for i, id := range r.order {
+		if id == schemaID {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return buf.offsets
+}
+
+// forgetOutstandingLocked removes offsets from their partitions'
+// outstanding sets without attempting to advance a commit. Used when a
+// buffer is discarded (eviction, dead-letter) rather than successfully
+// reassembled, so it doesn't permanently block later completions from
+// advancing past it - the next successful reassembly on that partition
+// will compute and commit the now-available watermark. Callers must hold
+// r.mu.
+func (r *Reassembler) forgetOutstandingLocked(offsets []offsetRef) {
+	for _, o := range offsets {
+		if prog, ok := r.progress[partitionKey(o.topic, o.partition)]; ok {
+			delete(prog.outstanding, o.offset)
+		}
+	}
+}
+
+// sweepExpired periodically hands schema IDs that have sat incomplete past
+// PartTimeout to DeadLetter.
+func (r *Reassembler) sweepExpired(stop <-chan struct{}) {
+	if r.PartTimeout <= 0 || r.DeadLetter == nil {
+		return
+	}
+
+	ticker := time.NewTicker(r.PartTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.sweepOnce()
+		}
+	}
+}
+
+func (r *Reassembler) sweepOnce() {
+	r.mu.Lock()
+	var expired []string
+	for id, buf := range r.buffers {
+		if time.Since(buf.firstSeen) > r.PartTimeout {
+			expired = append(expired, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, id := range expired {
+		r.mu.Lock()
+		buf, ok := r.buffers[id]
+		var offsets []offsetRef
+		if ok {
+			offsets = r.removeLocked(id)
+			r.forgetOutstandingLocked(offsets)
+		}
+		r.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		if err := r.DeadLetter.Send(id, buf.headers, buf.parts); err != nil {
+			log.Println("Error sending expired schema_id to dead letter:", id, err)
+		}
+	}
+}
+
+func headerMap(headers []kafka.Header) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[h.Key] = string(h.Value)
+	}
+	return m
+}
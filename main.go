@@ -13,46 +13,190 @@ import (
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/segmentio/kafka-go"
+
+	"github.com/uttom41/go_server_client/avro"
+	"github.com/uttom41/go_server_client/bootstrap"
+	"github.com/uttom41/go_server_client/claimcheck"
+	"github.com/uttom41/go_server_client/partitioner"
+	"github.com/uttom41/go_server_client/position"
+	"github.com/uttom41/go_server_client/schema"
 )
 
 var db *gorm.DB
 var err error
 
-// Column represents a column in the table
-type Column struct {
-	Name       string `json:"name"`
-	DataType   string `json:"data_type"`
-	IsNullable bool   `json:"is_nullable"`
-	IsPrimary  bool   `json:"is_primary"`
+// BinlogPosition is the tuple that lets a restart resume streaming from
+// exactly where it left off. It is an alias of position.Binlog so the
+// bootstrap subsystem can hand off a captured position without importing
+// package main.
+type BinlogPosition = position.Binlog
+
+// AvroConfig turns on Avro encoding against a Schema Registry for the data
+// topic. The zero value (empty URL) leaves rows JSON-encoded.
+type AvroConfig struct {
+	SchemaRegistryURL string
+	Username          string
+	Password          string
+	IDCacheTTL        time.Duration
+}
+
+var avroCfg AvroConfig
+var avroRegistry *avro.Registry
+
+// partitionerImpl picks which of the three Partitioner implementations
+// routes rows to Kafka partitions. MurmurHashPartitioner is the default so
+// behavior matches a vanilla Kafka producer's key-based partitioning.
+var partitionerImpl partitioner.Partitioner = partitioner.MurmurHashPartitioner{}
+
+// kafkaBalancer adapts a partitioner.Partitioner to kafka.Balancer so
+// kafka-go's writer can use it directly.
+type kafkaBalancer struct {
+	p partitioner.Partitioner
+}
+
+func (b kafkaBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	idx := b.p.Partition(msg.Key, int32(len(partitions)))
+	return partitions[idx]
 }
 
-// Table represents a table in the schema
-type Table struct {
-	Name    string   `json:"name"`
-	Columns []Column `json:"columns"`
+// claimCheckCfg and claimCheckStorage are nil/zero by default, which keeps
+// publishPayload's behavior identical to a plain WriteMessages call. Set
+// both to enable offloading oversized batches to an object store instead
+// of chunking them.
+var claimCheckCfg claimcheck.Config
+var claimCheckStorage claimcheck.ExternalStorage
+
+// publishPayload writes payload to writer under key, offloading it to
+// claimCheckStorage first and publishing a claim-check envelope instead
+// when it exceeds claimCheckCfg.Threshold and storage is configured.
+func publishPayload(writer *kafka.Writer, key, payload []byte) error {
+	if claimCheckStorage != nil && claimCheckCfg.ShouldClaimCheck(len(payload)) {
+		env, err := claimcheck.Publish(context.Background(), claimCheckStorage, claimCheckCfg, payload)
+		if err != nil {
+			return err
+		}
+		payload, err = json.Marshal(env)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.WriteMessages(context.Background(), kafka.Message{Key: key, Value: payload})
 }
 
-// Schema represents the entire schema with multiple tables and the database name
-type Schema struct {
-	DatabaseName string  `json:"database_name"`
-	Tables       []Table `json:"tables"`
+// newAvroEncoder derives tableName's Avro schema from the tracker's cached
+// columns, registers it, and returns an encoder for its rows. The caller is
+// expected to reuse the result for every row of that table.
+func newAvroEncoder(tracker *schemaTracker, tableName string) (*avro.Encoder, error) {
+	if avroRegistry == nil {
+		avroRegistry = avro.NewRegistry(avro.RegistryConfig{
+			URL:        avroCfg.SchemaRegistryURL,
+			Username:   avroCfg.Username,
+			Password:   avroCfg.Password,
+			IDCacheTTL: avroCfg.IDCacheTTL,
+		})
+	}
+
+	columns, err := tracker.columns(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaJSON, err := avro.DeriveSchema(schema.Table{Name: tableName, Columns: columns})
+	if err != nil {
+		return nil, err
+	}
+
+	schemaID, err := avroRegistry.Register(tableName, schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return avro.NewEncoder(schemaID, schemaJSON)
+}
+
+// encodeRowEvent serializes evt's row image (the after-image, or the
+// before-image for a delete) for publishing. With no Avro encoder
+// configured it falls back to JSON-encoding the whole RowEvent envelope.
+func encodeRowEvent(encoder *avro.Encoder, evt RowEvent) ([]byte, error) {
+	if encoder == nil {
+		return json.Marshal(evt)
+	}
+
+	row := evt.After
+	if row == nil {
+		row = evt.Before
+	}
+	return encoder.Encode(row)
 }
 
-// TrackingTable stores information on previously sent data
+// SyncMode selects how a tracked table is kept flowing into Kafka.
+type SyncMode int
+
+const (
+	// SyncModeBinlog streams row events off the MySQL binlog. This is the
+	// default for any table with a primary key.
+	SyncModeBinlog SyncMode = iota
+	// SyncModePolling re-runs a "greater than the last ID I saw" query on
+	// an interval. It is the fallback for tables with no PK to anchor a
+	// binlog position to.
+	SyncModePolling
+)
+
+// TrackingTable stores the durable position CDC should resume from.
+//
+// BinlogFile/BinlogPos/GTIDSet anchor binlog-based CDC; LastSentID is kept
+// only for tables running in SyncModePolling (e.g. tables without a PK,
+// where there is no binlog position that makes sense to resume from).
 type TrackingTable struct {
 	TableName  string
+	Mode       SyncMode
+	ServerUUID string
+	BinlogFile string
+	BinlogPos  uint32
+	GTIDSet    string
 	LastSentID int64
 }
 
+// describeColumns runs DESCRIBE against an already-escaped table name and
+// returns its column layout. It is shared by GetSchema and schemaTracker so
+// the two never drift in how they interpret DESCRIBE's output.
+func describeColumns(db *gorm.DB, escapedTableName string) ([]schema.Column, error) {
+	columnRows, err := db.Raw(fmt.Sprintf("DESCRIBE %s", escapedTableName)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer columnRows.Close()
+
+	var columns []schema.Column
+	for columnRows.Next() {
+		var field, colType, null, key string
+		var defaultValue sql.NullString
+		var extra string
+		if err := columnRows.Scan(&field, &colType, &null, &key, &defaultValue, &extra); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, schema.Column{
+			Name:       field,
+			DataType:   colType,
+			IsNullable: null == "YES",
+			IsPrimary:  key == "PRI",
+		})
+	}
+
+	return columns, nil
+}
+
 // GetSchema uses GORM to fetch the schema
-func GetSchema(db *gorm.DB, dbName string) (Schema, error) {
-	var schema Schema
-	schema.DatabaseName = dbName
+func GetSchema(db *gorm.DB, dbName string) (schema.Schema, error) {
+	var sch schema.Schema
+	sch.DatabaseName = dbName
 
 	// Get the list of tables using GORM
 	rows, err := db.Raw("SHOW TABLES").Rows()
 	if err != nil {
-		return schema, err
+		return sch, err
 	}
 	defer rows.Close()
 
@@ -66,46 +210,28 @@ func GetSchema(db *gorm.DB, dbName string) (Schema, error) {
 		var tableName string
 		err = rows.Scan(&tableName)
 		if err != nil {
-			return schema, err
+			return sch, err
 		}
 
 		// Escape the table name to handle reserved keywords
 		tableName = fmt.Sprintf("`%s`", tableName)
 
-		// Get columns for each table
-		table := Table{Name: tableName}
-		columnRows, err := db.Raw(fmt.Sprintf("DESCRIBE %s", tableName)).Rows()
+		table := schema.Table{Name: tableName}
+		table.Columns, err = describeColumns(db, tableName)
 		if err != nil {
-			return schema, err
+			return sch, err
 		}
-		defer columnRows.Close()
-
-		for columnRows.Next() {
-			var field, colType, null, key string
-			var defaultValue sql.NullString
-			var extra string
-			err = columnRows.Scan(&field, &colType, &null, &key, &defaultValue, &extra)
-			if err != nil {
-				return schema, err
-			}
 
-			column := Column{
-				Name:       field,
-				DataType:   colType,
-				IsNullable: null == "YES",
-				IsPrimary:  key == "PRI",
-			}
-
-			table.Columns = append(table.Columns, column)
-		}
-
-		schema.Tables = append(schema.Tables, table)
+		sch.Tables = append(sch.Tables, table)
 	}
 
-	return schema, nil
+	return sch, nil
 }
 
-// Function to send schema data in chunks
+// sendSchemaInChunks splits a payload into multi-part Kafka messages. It is
+// only still needed for the one-shot schema topic; row data uses
+// publishPayload's claim-check path instead, since per-row messages don't
+// need fragile multi-part reassembly.
 func sendSchemaInChunks(writer *kafka.Writer, schemaData []byte) error {
 	chunkSize := 5 * 1024 * 1024 // Adjust based on Kafka message limits and payload requirements
 	totalParts := int(math.Ceil(float64(len(schemaData)) / float64(chunkSize)))
@@ -146,7 +272,11 @@ func createTrackingTableIfNotExists() {
 	result := db.Exec(`
         CREATE TABLE IF NOT EXISTS tracking_table (
             table_name VARCHAR(255) PRIMARY KEY,
-            last_sent_id BIGINT NOT NULL
+            server_uuid VARCHAR(36) NOT NULL DEFAULT '',
+            binlog_file VARCHAR(255) NOT NULL DEFAULT '',
+            binlog_pos BIGINT NOT NULL DEFAULT 0,
+            gtid_set TEXT,
+            last_sent_id BIGINT NOT NULL DEFAULT 0
         );
     `)
 	if result.Error != nil {
@@ -159,8 +289,86 @@ func createTrackingTableIfNotExists() {
 	log.Printf("Rows affected: %d", rowsAffected)
 }
 
-// fetchData fetches data from a given table since the last sent ID
-func fetchData(tableName string, lastSentID int64) ([]map[string]interface{}, int64, error) {
+// saveBinlogPosition persists where CDC should resume streaming from after
+// a restart, so a crash never re-reads (or skips) events.
+func saveBinlogPosition(tableName string, pos BinlogPosition) {
+	result := db.Exec(`
+        INSERT INTO tracking_table (table_name, server_uuid, binlog_file, binlog_pos, gtid_set)
+        VALUES (?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE server_uuid = ?, binlog_file = ?, binlog_pos = ?, gtid_set = ?
+    `, tableName, pos.ServerUUID, pos.File, pos.Pos, pos.GTIDSet,
+		pos.ServerUUID, pos.File, pos.Pos, pos.GTIDSet)
+	if result.Error != nil {
+		log.Println("Error saving binlog position:", result.Error)
+	}
+}
+
+// hasSavedPosition reports whether tableName already has a binlog position
+// recorded, meaning it has either streamed before or already been
+// bootstrapped.
+func hasSavedPosition(tableName string) bool {
+	var binlogFile sql.NullString
+	row := db.Raw("SELECT binlog_file FROM tracking_table WHERE table_name = ?", tableName).Row()
+	if err := row.Scan(&binlogFile); err != nil {
+		return false
+	}
+	return binlogFile.String != ""
+}
+
+// capturingSink wraps a bootstrap.BootstrapSink to also record the binlog
+// position Bootstrap captured per table, so bootstrapFreshTables can seed
+// streamSyncTable's starting point and guarantee an exactly-once handoff.
+type capturingSink struct {
+	bootstrap.BootstrapSink
+	positions map[string]position.Binlog
+}
+
+func (c *capturingSink) Done(table string, pos position.Binlog) error {
+	c.positions[table] = pos
+	return c.BootstrapSink.Done(table, pos)
+}
+
+// bootstrapFreshTables runs the initial snapshot for any binlog-mode table
+// that has never been synced before, seeding its tracking entry with the
+// binlog position captured at snapshot start so streaming picks up exactly
+// where the snapshot left off.
+func bootstrapFreshTables(trackingTables []TrackingTable) {
+	var fresh []string
+	for _, t := range trackingTables {
+		if t.Mode == SyncModeBinlog && !hasSavedPosition(t.TableName) {
+			fresh = append(fresh, t.TableName)
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+
+	sink, err := bootstrap.NewFileBootstrapSink("./bootstrap-snapshot")
+	if err != nil {
+		log.Println("Error preparing bootstrap sink:", err)
+		return
+	}
+	capturing := &capturingSink{BootstrapSink: sink, positions: make(map[string]position.Binlog)}
+
+	if err := bootstrap.Bootstrap(db, fresh, capturing); err != nil {
+		log.Println("Error bootstrapping initial snapshot:", err)
+		return
+	}
+
+	for i := range trackingTables {
+		if pos, ok := capturing.positions[trackingTables[i].TableName]; ok {
+			trackingTables[i].BinlogFile = pos.File
+			trackingTables[i].BinlogPos = pos.Pos
+			trackingTables[i].GTIDSet = pos.GTIDSet
+			saveBinlogPosition(trackingTables[i].TableName, pos)
+		}
+	}
+}
+
+// pollTable fetches rows from a given table since the last sent ID. It is
+// the fallback path for tables with no primary key to anchor a binlog
+// position to.
+func pollTable(tableName string, lastSentID int64) ([]map[string]interface{}, int64, error) {
 	var rows *sql.Rows
 	var err error
 	query := fmt.Sprintf("SELECT * FROM %s WHERE id > ? ORDER BY id ASC LIMIT 1000", tableName)
@@ -199,10 +407,25 @@ func fetchData(tableName string, lastSentID int64) ([]map[string]interface{}, in
 	return data, maxID, nil
 }
 
-// syncTable periodically syncs data from the Prism DB to Kafka
-func syncTable(tableName string, tracking *TrackingTable, writer *kafka.Writer) {
+// syncTable keeps a table flowing into Kafka, using binlog-based CDC by
+// default and falling back to polling for tables without a PK (see the
+// PrimaryKeyColumns check in main). tracker supplies the cached column layout the binlog
+// decoder needs to make sense of row images across DDL changes.
+func syncTable(tableName string, tracking *TrackingTable, writer *kafka.Writer, tracker *schemaTracker) {
+	if tracking.Mode == SyncModePolling {
+		pollSyncTable(tableName, tracking, writer)
+		return
+	}
+	streamSyncTable(tableName, tracking, writer, tracker)
+}
+
+// pollSyncTable periodically syncs data from the Prism DB to Kafka by
+// re-running the "greater than the last ID I saw" query every minute. It
+// cannot see UPDATEs/DELETEs and lags by up to a minute; streamSyncTable is
+// preferred wherever a PK makes CDC possible.
+func pollSyncTable(tableName string, tracking *TrackingTable, writer *kafka.Writer) {
 	for {
-		data, lastID, err := fetchData(tableName, tracking.LastSentID)
+		data, lastID, err := pollTable(tableName, tracking.LastSentID)
 		if err != nil {
 			log.Println("Error fetching data:", err)
 			time.Sleep(10 * time.Second)
@@ -215,7 +438,7 @@ func syncTable(tableName string, tracking *TrackingTable, writer *kafka.Writer)
 			if err != nil {
 				log.Fatal("Error serializing schema:", err)
 			}
-			err = sendSchemaInChunks(writer, schemaJSON)
+			err = publishPayload(writer, []byte(tableName), schemaJSON)
 			if err != nil {
 				log.Println("Error sending data to Kafka:", err)
 				time.Sleep(10 * time.Second)
@@ -240,6 +463,63 @@ func syncTable(tableName string, tracking *TrackingTable, writer *kafka.Writer)
 	}
 }
 
+// streamSyncTable consumes row events for tableName off its own binlog
+// replication connection and publishes each one to Kafka as it arrives, persisting the
+// binlog position after every event so a restart resumes exactly where it
+// left off.
+func streamSyncTable(tableName string, tracking *TrackingTable, writer *kafka.Writer, tracker *schemaTracker) {
+	streamer, err := newBinlogStreamer(db, tracker, BinlogPosition{
+		ServerUUID: tracking.ServerUUID,
+		File:       tracking.BinlogFile,
+		Pos:        tracking.BinlogPos,
+		GTIDSet:    tracking.GTIDSet,
+	})
+	if err != nil {
+		log.Println("Error starting binlog streamer, falling back to polling:", err)
+		pollSyncTable(tableName, tracking, writer)
+		return
+	}
+	defer streamer.Close()
+
+	var encoder *avro.Encoder
+	if avroCfg.SchemaRegistryURL != "" {
+		encoder, err = newAvroEncoder(tracker, tableName)
+		if err != nil {
+			log.Println("Error preparing avro encoder, falling back to JSON:", err)
+		}
+	}
+
+	columns, err := tracker.columns(tableName)
+	if err != nil {
+		log.Println("Error describing", tableName, "for partition key:", err)
+		return
+	}
+	pkCols := (schema.Table{Columns: columns}).PrimaryKeyColumns()
+
+	err = streamer.StreamTable(tableName, func(evt RowEvent, pos BinlogPosition) error {
+		payload, err := encodeRowEvent(encoder, evt)
+		if err != nil {
+			return err
+		}
+
+		row := evt.After
+		if row == nil {
+			row = evt.Before
+		}
+
+		if err := publishPayload(writer, partitioner.CanonicalKey(row, pkCols), payload); err != nil {
+			return err
+		}
+
+		tracking.ServerUUID, tracking.BinlogFile, tracking.BinlogPos, tracking.GTIDSet = pos.ServerUUID, pos.File, pos.Pos, pos.GTIDSet
+		saveBinlogPosition(tableName, pos)
+		return nil
+	})
+	if err != nil {
+		log.Println("Binlog stream for table", tableName, "ended with error:", err)
+	}
+}
+
 func main() {
 	// MySQL connection string
 	db, err = gorm.Open("mysql", "root:12345678@tcp(192.168.10.114:3306)/prism_db?charset=utf8&parseTime=True&loc=Local")
@@ -276,7 +556,7 @@ func main() {
 	dataTopic := kafka.NewWriter(kafka.WriterConfig{
 		Brokers:          []string{"localhost:9092"},
 		Topic:            "data",
-		Balancer:         &kafka.LeastBytes{},
+		Balancer:         kafkaBalancer{p: partitionerImpl},
 		CompressionCodec: kafka.Lz4.Codec(),
 		BatchSize:        500,              // Reduce if necessary to control message size
 		BatchBytes:       10 * 1024 * 1024, // 1MB (or set appropriately)
@@ -296,17 +576,37 @@ func main() {
 
 	// Define the tables you want to track
 	trackingTables := []TrackingTable{
-		{TableName: "accounts", LastSentID: 0},
-		{TableName: "account_balances", LastSentID: 0},
-		{TableName: "attendance", LastSentID: 0},
-		{TableName: "account_orders", LastSentID: 0},
-		{TableName: "asset_masters", LastSentID: 0},
+		{TableName: "accounts", Mode: SyncModeBinlog},
+		{TableName: "account_balances", Mode: SyncModeBinlog},
+		{TableName: "attendance", Mode: SyncModeBinlog},
+		{TableName: "account_orders", Mode: SyncModeBinlog},
+		{TableName: "asset_masters", Mode: SyncModeBinlog},
 		// Add more tables as needed
 	}
 
-	// Start syncing each table concurrently
-	for _, tracking := range trackingTables {
-		go syncTable(tracking.TableName, &tracking, dataTopic)
+	tracker := newSchemaTracker(db, "prism_db")
+
+	// Tables without a PK have no stable row identity for CDC to key on,
+	// so they stay on the polling fallback regardless of the default above.
+	for i := range trackingTables {
+		columns, err := tracker.columns(trackingTables[i].TableName)
+		if err != nil {
+			log.Println("Error inspecting schema for", trackingTables[i].TableName, err)
+			continue
+		}
+		if len(schema.Table{Columns: columns}.PrimaryKeyColumns()) == 0 {
+			log.Printf("Table %s has no primary key, falling back to polling\n", trackingTables[i].TableName)
+			trackingTables[i].Mode = SyncModePolling
+		}
+	}
+
+	bootstrapFreshTables(trackingTables)
+
+	// Start syncing each table concurrently. Each goroutine gets its own
+	// *TrackingTable (rather than closing over the loop variable) so their
+	// persisted binlog positions don't clobber one another.
+	for i := range trackingTables {
+		go syncTable(trackingTables[i].TableName, &trackingTables[i], dataTopic, tracker)
 	}
 
 	log.Println("Data published to Kafka successfully.")
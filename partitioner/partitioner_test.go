@@ -0,0 +1,59 @@
+package partitioner
+
+import "testing"
+
+// rowsWithSamePK are two distinct row images that share a primary key, the
+// way an UPDATE's before/after images (or a row re-read after a restart)
+// would. Every implementation must route both to the same partition.
+var rowsWithSamePK = []map[string]interface{}{
+	{"id": 42, "name": "first-seen"},
+	{"id": 42, "name": "renamed-later"},
+}
+
+var pkCols = []string{"id"}
+
+func TestPartitioners_SamePKSamePartition(t *testing.T) {
+	impls := map[string]Partitioner{
+		"murmur": MurmurHashPartitioner{},
+		"fnv32":  Fnv32Partitioner{},
+		"jump":   JumpHashPartitioner{},
+	}
+
+	for name, p := range impls {
+		p := p
+		t.Run(name, func(t *testing.T) {
+			for _, numPartitions := range []int32{1, 3, 12} {
+				var want int32 = -1
+				for _, row := range rowsWithSamePK {
+					got := p.Partition(p.Key(row, pkCols), numPartitions)
+					if want == -1 {
+						want = got
+						continue
+					}
+					if got != want {
+						t.Fatalf("numPartitions=%d: row %v landed on partition %d, want %d (same PK as the first row)", numPartitions, row, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestPartitioners_StableAcrossRestarts simulates a restart by building the
+// key and computing the partition twice independently (a fresh Partitioner
+// value each time, as a new process would have). The result must match,
+// since tracking_table only persists the binlog position, not partition
+// assignments.
+func TestPartitioners_StableAcrossRestarts(t *testing.T) {
+	row := map[string]interface{}{"id": 7}
+	impls := []Partitioner{MurmurHashPartitioner{}, Fnv32Partitioner{}, JumpHashPartitioner{}}
+
+	for _, p := range impls {
+		before := p.Partition(p.Key(row, pkCols), 8)
+		restarted := Partitioner(p) // fresh value, same as after a process restart
+		after := restarted.Partition(restarted.Key(row, pkCols), 8)
+		if before != after {
+			t.Fatalf("%T: partition changed across restart: %d != %d", p, before, after)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// Package position holds the binlog coordinate CDC and bootstrap need to
+// agree on, so the snapshot-to-stream handoff is exactly-once: bootstrap
+// records where streaming should pick up, and the CDC subsystem resumes
+// from exactly that point.
+package position
+
+// Binlog is the tuple that lets a restart (or a bootstrap handoff) resume
+// streaming from exactly where it left off.
+type Binlog struct {
+	ServerUUID string
+	File       string
+	Pos        uint32
+	GTIDSet    string
+}
@@ -0,0 +1,36 @@
+// Package schema holds the plain data types describing a MySQL schema as
+// discovered by GetSchema, shared by main and the encoders/sinks that need
+// to reason about table layout without importing package main.
+package schema
+
+// Column represents a column in the table
+type Column struct {
+	Name       string `json:"name"`
+	DataType   string `json:"data_type"`
+	IsNullable bool   `json:"is_nullable"`
+	IsPrimary  bool   `json:"is_primary"`
+}
+
+// Table represents a table in the schema
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+}
+
+// Schema represents the entire schema with multiple tables and the database name
+type Schema struct {
+	DatabaseName string  `json:"database_name"`
+	Tables       []Table `json:"tables"`
+}
+
+// PrimaryKeyColumns returns the names of a table's PK columns, in
+// declaration order.
+func (t Table) PrimaryKeyColumns() []string {
+	var pk []string
+	for _, c := range t.Columns {
+		if c.IsPrimary {
+			pk = append(pk, c.Name)
+		}
+	}
+	return pk
+}